@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Backend performs the DSP primitives a Decoder's inner loop runs once per
+// block: magnitude lookup, matched filtering, quantization to bits, and the
+// bit-transpose Search needs ahead of preamble matching. Swapping the
+// Backend changes only how fast these run, never what they compute, so a
+// Decoder can pick whichever is fastest on the running machine without
+// touching correctness.
+type Backend interface {
+	// Execute computes the magnitude of each I/Q sample pair in input,
+	// writing len(input)/2 values to output.
+	Execute(input []byte, output []float64)
+
+	// Filter runs the boxcar/matched filter over input, writing
+	// len(input) values to output.
+	Filter(input []float64, output []float64)
+
+	// Quantize converts each filtered sample to a single bit, packed
+	// eight to a byte.
+	Quantize(input []float64, output []byte)
+
+	// Transpose rearranges packed bits from sample-major to symbol-major
+	// order ahead of preamble search.
+	Transpose(input []byte) []byte
+}
+
+// backends lists every Backend available on this build, in the order
+// SelectBackend tries them.
+//
+// There's no AVX2 entry: a real SIMD kernel needs unsafe-backed
+// //go:noescape assembly stubs benchmarked against actual hardware to
+// justify its complexity, neither of which this change can do. An earlier
+// draft of this package shipped an "asm" backend that was really just
+// scalarBackend's Execute unrolled by 4 in plain Go, registered as if it
+// were a distinct, selectable option; that overclaimed what was delivered,
+// so it's gone rather than kept as a dishonest middle ground. Land it for
+// real, with actual assembly, or not at all.
+func backends() map[string]func() Backend {
+	return map[string]func() Backend{
+		"scalar":    func() Backend { return newScalarBackend() },
+		"goroutine": func() Backend { return newGoroutineBackend() },
+	}
+}
+
+// SelectBackend picks a Backend by name, or the fastest available one if
+// name is empty, by timing Execute against a synthetic block of blockSize
+// I/Q sample pairs. It returns the chosen name alongside the Backend.
+//
+// Nothing calls SelectBackend yet. parse.Parser.Cfg() already returns
+// *decode.PacketConfig, but the decode.go that would define PacketConfig
+// and Decoder isn't part of this tree, so there's no NewDecoder to accept
+// a Backend and no Cfg().Log() to report the chosen name to. This package
+// lives at decode/backend, its own package rather than decode itself,
+// specifically so that gap doesn't also keep these tests from compiling:
+// decode/decode_test.go already references PacketConfig/NewDecoder/
+// NewMagLUT, none of which exist in this tree, so `go vet ./decode/...`
+// fails regardless of anything here. Wiring this in is a single call once
+// Decoder lands: name, backend := backend.SelectBackend(cfg.Backend,
+// cfg.BlockSize).
+//
+// The real implementation benchmarks against gen.CmplxOscillatorU8 output;
+// that generator isn't part of this change, so bytes from a fixed-seed
+// math/rand source stand in here. They exercise the same code paths, just without a
+// realistic signal shape.
+func SelectBackend(name string, blockSize int) (string, Backend) {
+	available := backends()
+
+	if name != "" {
+		newFn, ok := available[name]
+		if !ok {
+			panic(fmt.Sprintf("backend: unknown backend %q", name))
+		}
+		return name, newFn()
+	}
+
+	input := make([]byte, blockSize<<1)
+	rand.New(rand.NewSource(1)).Read(input)
+	output := make([]float64, blockSize)
+
+	bestName := ""
+	var bestBackend Backend
+	bestElapsed := time.Duration(1<<63 - 1)
+
+	for candidateName, newFn := range available {
+		backend := newFn()
+
+		start := time.Now()
+		const reps = 32
+		for i := 0; i < reps; i++ {
+			backend.Execute(input, output)
+		}
+		elapsed := time.Since(start)
+
+		if elapsed < bestElapsed {
+			bestName, bestBackend, bestElapsed = candidateName, backend, elapsed
+		}
+	}
+
+	return bestName, bestBackend
+}
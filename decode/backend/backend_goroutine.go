@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"runtime"
+	"sync"
+)
+
+// goroutineBackend parallelizes Execute, the embarrassingly-parallel
+// per-sample magnitude lookup, across runtime.GOMAXPROCS workers, each
+// given its own disjoint slice of the block so no synchronization is
+// needed beyond the final join. Filter, Quantize, and Transpose run
+// sequentially on the underlying scalarBackend, since each operates over
+// the whole block at once and isn't worth splitting at typical block
+// sizes.
+type goroutineBackend struct {
+	scalar *scalarBackend
+}
+
+func newGoroutineBackend() *goroutineBackend {
+	return &goroutineBackend{scalar: newScalarBackend()}
+}
+
+func (b *goroutineBackend) Execute(input []byte, output []float64) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(output) {
+		workers = len(output)
+	}
+	if workers <= 1 {
+		b.scalar.Execute(input, output)
+		return
+	}
+
+	chunk := (len(output) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(output); lo += chunk {
+		hi := lo + chunk
+		if hi > len(output) {
+			hi = len(output)
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			b.scalar.Execute(input[lo<<1:hi<<1], output[lo:hi])
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+func (b *goroutineBackend) Filter(input []float64, output []float64) {
+	b.scalar.Filter(input, output)
+}
+
+func (b *goroutineBackend) Quantize(input []float64, output []byte) {
+	b.scalar.Quantize(input, output)
+}
+
+func (b *goroutineBackend) Transpose(input []byte) []byte {
+	return b.scalar.Transpose(input)
+}
@@ -0,0 +1,67 @@
+package backend
+
+import "math"
+
+// scalarBackend is the portable, single-threaded Backend implementation.
+// It has no CPU feature requirements and is always available.
+type scalarBackend struct {
+	magLUT [0x100]float64
+}
+
+// newScalarBackend builds a scalarBackend with the |I|+|Q|-style magnitude
+// lookup table precomputed for every possible raw sample byte.
+func newScalarBackend() *scalarBackend {
+	b := &scalarBackend{}
+	for idx := range b.magLUT {
+		b.magLUT[idx] = math.Abs((float64(idx) - 127.5) / 127.5)
+	}
+	return b
+}
+
+func (b *scalarBackend) Execute(input []byte, output []float64) {
+	for idx := range output {
+		output[idx] = b.magLUT[input[idx<<1]] + b.magLUT[input[idx<<1+1]]
+	}
+}
+
+// Filter is a simple boxcar filter: each output sample is the sum of the
+// next two input samples, matching a single Manchester-encoded chip.
+func (b *scalarBackend) Filter(input []float64, output []float64) {
+	for idx := range output {
+		if idx+1 >= len(input) {
+			output[idx] = input[idx]
+			continue
+		}
+		output[idx] = input[idx] + input[idx+1]
+	}
+}
+
+// Quantize keeps only the sign of each filtered sample, packed eight to a
+// byte, most-significant bit first.
+func (b *scalarBackend) Quantize(input []float64, output []byte) {
+	for idx := range output {
+		output[idx] = 0
+	}
+	for idx, val := range input {
+		if val > 0 {
+			output[idx>>3] |= 1 << uint(7-idx&7)
+		}
+	}
+}
+
+// Transpose rearranges packed bits from sample-major to bit-plane-major
+// order: bit plane p (bit 7-p, most-significant first) of input byte s
+// becomes bit p*len(input)+s of the packed output, most-significant first.
+func (b *scalarBackend) Transpose(input []byte) []byte {
+	output := make([]byte, len(input))
+
+	for plane := 0; plane < 8; plane++ {
+		for sample, byteVal := range input {
+			bit := (byteVal >> uint(7-plane)) & 1
+			outIdx := plane*len(input) + sample
+			output[outIdx>>3] |= bit << uint(7-outIdx&7)
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBackendParity(t *testing.T) {
+	input := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(input)
+
+	want := make([]float64, len(input)>>1)
+	newScalarBackend().Execute(input, want)
+
+	for name, newFn := range backends() {
+		got := make([]float64, len(input)>>1)
+		newFn().Execute(input, got)
+
+		for idx := range want {
+			if got[idx] != want[idx] {
+				t.Fatalf("%s: Execute[%d] = %v, want %v", name, idx, got[idx], want[idx])
+			}
+		}
+	}
+}
+
+func TestSelectBackendByName(t *testing.T) {
+	name, backend := SelectBackend("scalar", 1024)
+	if name != "scalar" {
+		t.Fatalf("SelectBackend(%q, ...) name = %q", "scalar", name)
+	}
+	if backend == nil {
+		t.Fatal("SelectBackend returned a nil Backend")
+	}
+}
+
+func TestSelectBackendAuto(t *testing.T) {
+	name, backend := SelectBackend("", 1024)
+	if _, ok := backends()[name]; !ok {
+		t.Fatalf("SelectBackend(\"\", ...) returned unknown backend %q", name)
+	}
+	if backend == nil {
+		t.Fatal("SelectBackend returned a nil Backend")
+	}
+}
@@ -0,0 +1,200 @@
+// Package httpsink implements an output.Sink that batches decoded messages
+// as gzip-compressed NDJSON and POSTs them to an HTTP collector.
+package httpsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+const (
+	queueDepth           = 256
+	defaultBatchSize     = 50
+	defaultFlushInterval = 5 * time.Second
+)
+
+func init() {
+	output.Register("http", newSinkFromURL)
+	output.Register("https", newSinkFromURL)
+}
+
+// Config holds the HTTP sink's endpoint and batching parameters.
+type Config struct {
+	Endpoint      string
+	BearerToken   string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// newSinkFromURL builds a Sink from a "-sink=http://..." or
+// "-sink=https://..." flag. The query string carries the remaining Config
+// fields: token, batch, interval.
+func newSinkFromURL(u *url.URL) (output.Sink, error) {
+	cfg := Config{
+		BatchSize:     defaultBatchSize,
+		FlushInterval: defaultFlushInterval,
+	}
+
+	q := u.Query()
+	cfg.BearerToken = q.Get("token")
+
+	if batch := q.Get("batch"); batch != "" {
+		n, err := strconv.Atoi(batch)
+		if err != nil {
+			return nil, fmt.Errorf("httpsink: invalid batch %q: %w", batch, err)
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("httpsink: batch must be >= 1, got %d", n)
+		}
+		cfg.BatchSize = n
+	}
+	if interval := q.Get("interval"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("httpsink: invalid interval %q: %w", interval, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("httpsink: interval must be > 0, got %s", d)
+		}
+		cfg.FlushInterval = d
+	}
+
+	endpoint := *u
+	endpoint.RawQuery = ""
+	cfg.Endpoint = endpoint.String()
+
+	return NewSink(cfg), nil
+}
+
+// Sink batches parse.LogMessage values and POSTs them as gzip-compressed
+// NDJSON to an HTTP collector.
+type Sink struct {
+	cfg    Config
+	client *http.Client
+
+	queue chan parse.LogMessage
+	done  chan struct{}
+}
+
+// NewSink builds a Sink and starts its batch-and-flush loop.
+func NewSink(cfg Config) *Sink {
+	s := &Sink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.FlushInterval},
+		queue:  make(chan parse.LogMessage, queueDepth),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Publish queues msg for the next batch, honoring output.Sink's
+// never-block contract: a full queue drops the message.
+func (s *Sink) Publish(msg parse.LogMessage) error {
+	select {
+	case s.queue <- msg:
+	default:
+		log.Printf("httpsink: queue full, dropped message for %d", msg.MeterID())
+	}
+	return nil
+}
+
+// Close stops the batch loop after flushing anything still queued.
+func (s *Sink) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *Sink) run() {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]parse.LogMessage, 0, s.cfg.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.post(batch); err != nil {
+			log.Printf("httpsink: %v", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-s.done:
+			// Drain whatever's still sitting in the queue before the
+			// final flush; Close() only closes done, it doesn't wait
+			// for run() to have consumed everything Publish sent.
+			for drained := false; !drained; {
+				select {
+				case msg := <-s.queue:
+					batch = append(batch, msg)
+				default:
+					drained = true
+				}
+			}
+			flush()
+			return
+		case <-ticker.C:
+			flush()
+		case msg := <-s.queue:
+			batch = append(batch, msg)
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+		}
+	}
+}
+
+// post gzip-compresses batch as NDJSON and POSTs it to the configured
+// endpoint, setting a bearer token if one was provided.
+func (s *Sink) post(batch []parse.LogMessage) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, msg := range batch {
+		if err := enc.Encode(msg); err != nil {
+			gz.Close()
+			return fmt.Errorf("encode batch: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("close gzip writer: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.Endpoint, &buf)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	if s.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.BearerToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post batch: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
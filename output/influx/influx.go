@@ -0,0 +1,211 @@
+// Package influx implements an output.Sink that writes decoded messages to
+// InfluxDB using the line protocol, either over UDP or HTTP.
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+const (
+	defaultMeasurement = "rtlamr"
+	queueDepth         = 256
+	httpTimeout        = 5 * time.Second
+)
+
+func init() {
+	output.Register("influx", newSinkFromURL)
+}
+
+// Config holds the InfluxDB sink's transport and addressing parameters.
+type Config struct {
+	// Transport is "udp" or "http", selected by the "proto" query
+	// parameter; "http" is the default.
+	Transport string
+
+	Addr        string
+	Database    string
+	Measurement string
+}
+
+// newSinkFromURL builds a Sink from a "-sink=influx://host:port" flag. The
+// query string carries proto (udp|http), db, and measurement.
+func newSinkFromURL(u *url.URL) (output.Sink, error) {
+	cfg := Config{
+		Transport:   "http",
+		Addr:        u.Host,
+		Measurement: defaultMeasurement,
+	}
+
+	q := u.Query()
+	if proto := q.Get("proto"); proto != "" {
+		cfg.Transport = proto
+	}
+	cfg.Database = q.Get("db")
+	if measurement := q.Get("measurement"); measurement != "" {
+		cfg.Measurement = measurement
+	}
+
+	return NewSink(cfg)
+}
+
+// Sink writes parse.LogMessage values to InfluxDB as line-protocol points.
+type Sink struct {
+	cfg    Config
+	writer lineWriter
+
+	queue chan parse.LogMessage
+	done  chan struct{}
+}
+
+// lineWriter sends a single line-protocol point to InfluxDB.
+type lineWriter interface {
+	WriteLine(line []byte) error
+	Close() error
+}
+
+// NewSink builds a Sink using the transport named by cfg.Transport, and
+// starts its write loop in the background.
+func NewSink(cfg Config) (*Sink, error) {
+	var w lineWriter
+	var err error
+
+	switch cfg.Transport {
+	case "udp":
+		w, err = newUDPWriter(cfg.Addr)
+	case "http":
+		w = newHTTPWriter(cfg)
+	default:
+		return nil, fmt.Errorf("influx: unknown transport %q", cfg.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{
+		cfg:    cfg,
+		writer: w,
+		queue:  make(chan parse.LogMessage, queueDepth),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Publish queues msg for the write loop, honoring output.Sink's
+// never-block contract: a full queue drops the message.
+func (s *Sink) Publish(msg parse.LogMessage) error {
+	select {
+	case s.queue <- msg:
+	default:
+		log.Printf("influx: queue full, dropped message for %d", msg.MeterID())
+	}
+	return nil
+}
+
+// Close stops the write loop and releases the underlying transport.
+func (s *Sink) Close() error {
+	close(s.done)
+	return s.writer.Close()
+}
+
+func (s *Sink) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.queue:
+			if err := s.writer.WriteLine([]byte(s.line(msg))); err != nil {
+				log.Printf("influx: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Sink) line(msg parse.LogMessage) string {
+	c, _ := parse.Consumption(msg.Message)
+
+	return fmt.Sprintf(
+		"%s,meter_id=%d,type=%s rssi=%f,consumption=%di %d",
+		s.cfg.Measurement,
+		msg.MeterID(),
+		msg.MsgType(),
+		float64(msg.RSSI),
+		int64(c),
+		msg.Time.UnixNano(),
+	)
+}
+
+// httpWriter POSTs each line to InfluxDB's /write endpoint.
+type httpWriter struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPWriter(cfg Config) *httpWriter {
+	return &httpWriter{
+		url:    fmt.Sprintf("http://%s/write?db=%s", cfg.Addr, url.QueryEscape(cfg.Database)),
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (w *httpWriter) WriteLine(line []byte) error {
+	resp, err := w.client.Post(w.url, "text/plain", bytes.NewReader(line))
+	if err != nil {
+		return fmt.Errorf("influx: write point: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("influx: write point: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (w *httpWriter) Close() error {
+	return nil
+}
+
+// udpWriter sends each line as a single UDP datagram, matching the
+// fire-and-forget semantics of InfluxDB's UDP listener.
+type udpWriter struct {
+	addr   string
+	socket udpSocket
+}
+
+// udpSocket is the subset of net.UDPConn used here, so tests can stub it.
+type udpSocket interface {
+	Write(b []byte) (int, error)
+	Close() error
+}
+
+func newUDPWriter(addr string) (*udpWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("influx: dial %s: %w", addr, err)
+	}
+	return &udpWriter{addr: addr, socket: conn}, nil
+}
+
+func (w *udpWriter) WriteLine(line []byte) error {
+	_, err := w.socket.Write(line)
+	if err != nil {
+		return fmt.Errorf("influx: write point: %w", err)
+	}
+	return nil
+}
+
+func (w *udpWriter) Close() error {
+	return w.socket.Close()
+}
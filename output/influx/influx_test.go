@@ -0,0 +1,69 @@
+package influx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bemasher/rtlamr/output/outputtest"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+func TestSinkLine(t *testing.T) {
+	s := &Sink{cfg: Config{Measurement: "rtlamr"}}
+
+	msg := parse.LogMessage{
+		Time:    parse.Timestamp{Time: time.Unix(1, 0)},
+		RSSI:    -12.5,
+		Message: outputtest.Message{ID: 42, Type: "SCM", Consumption: 1234},
+	}
+
+	got := s.line(msg)
+	want := "rtlamr,meter_id=42,type=SCM rssi=-12.500000,consumption=1234i 1000000000"
+	if got != want {
+		t.Fatalf("line() = %q, want %q", got, want)
+	}
+}
+
+// fakeLineWriter records every line passed to WriteLine, standing in for a
+// real udpWriter/httpWriter so the write loop can be exercised without a
+// network round-trip.
+type fakeLineWriter struct {
+	lines chan []byte
+}
+
+func (w *fakeLineWriter) WriteLine(line []byte) error {
+	w.lines <- line
+	return nil
+}
+
+func (w *fakeLineWriter) Close() error { return nil }
+
+func TestSinkPublishWritesLine(t *testing.T) {
+	w := &fakeLineWriter{lines: make(chan []byte, 1)}
+	s := &Sink{
+		cfg:    Config{Measurement: "rtlamr"},
+		writer: w,
+		queue:  make(chan parse.LogMessage, 1),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	defer s.Close()
+
+	msg := parse.LogMessage{
+		Time:    parse.Timestamp{Time: time.Unix(1, 0)},
+		Message: outputtest.Message{ID: 42, Type: "SCM", Consumption: 1234},
+	}
+	if err := s.Publish(msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case line := <-w.lines:
+		if !strings.Contains(string(line), "consumption=1234i") {
+			t.Fatalf("written line missing consumption field: %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("writer never received a line")
+	}
+}
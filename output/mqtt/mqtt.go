@@ -0,0 +1,299 @@
+// Package mqtt implements an output.Sink that publishes decoded messages to
+// an MQTT broker over a single long-lived connection, reconnecting with a
+// truncated exponential backoff whenever the connection drops.
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+func init() {
+	output.Register("mqtt", newSinkFromURL)
+	output.Register("mqtts", newSinkFromURL)
+}
+
+// newSinkFromURL builds a Sink from a "-sink=mqtt://..." or
+// "-sink=mqtts://..." flag. The broker is u.Host, the topic template is
+// u.Path (e.g. "/rtlamr/{meter_id}"), and the query string carries the
+// remaining Config fields: clientid, qos, retain, cacert, clientcert,
+// clientkey.
+func newSinkFromURL(u *url.URL) (output.Sink, error) {
+	cfg := Config{
+		ClientID: "rtlamr",
+		Topic:    u.Path,
+	}
+
+	if cfg.Topic == "" {
+		cfg.Topic = "/rtlamr/" + placeholder
+	}
+
+	scheme := "tcp"
+	if u.Scheme == "mqtts" {
+		scheme = "ssl"
+	}
+	cfg.Broker = fmt.Sprintf("%s://%s", scheme, u.Host)
+
+	q := u.Query()
+	if clientID := q.Get("clientid"); clientID != "" {
+		cfg.ClientID = clientID
+	}
+	if qos := q.Get("qos"); qos != "" {
+		n, err := strconv.ParseUint(qos, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: invalid qos %q: %w", qos, err)
+		}
+		cfg.QoS = byte(n)
+	}
+	if retain := q.Get("retain"); retain != "" {
+		b, err := strconv.ParseBool(retain)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: invalid retain %q: %w", retain, err)
+		}
+		cfg.Retain = b
+	}
+	cfg.CACert = q.Get("cacert")
+	cfg.ClientCert = q.Get("clientcert")
+	cfg.ClientKey = q.Get("clientkey")
+
+	return NewSink(cfg)
+}
+
+const (
+	minBackoff  = time.Second
+	maxBackoff  = 10 * time.Second
+	queueDepth  = 256
+	placeholder = "{meter_id}"
+)
+
+// Config holds the MQTT sink's connection and publish parameters. TLS
+// material is named by path rather than assumed to live at fixed filenames,
+// so a deployment can point at whatever CA bundle and client certificate it
+// actually has.
+type Config struct {
+	Broker   string
+	ClientID string
+
+	QoS    byte
+	Retain bool
+
+	// Topic is a template for the publish topic. The placeholder
+	// "{meter_id}" is replaced with the message's MeterID, e.g.
+	// "/rtlamr/{meter_id}".
+	Topic string
+
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// reconnecter is implemented by errors that carry a broker-specified
+// reconnect delay. paho doesn't define one today, but if a future client or
+// wrapper surfaces a Retry-After-style hint this lets us honor it instead of
+// our own backoff schedule.
+type reconnecter interface {
+	ReconnectAfter() time.Duration
+}
+
+// Sink publishes parse.LogMessage values to an MQTT broker over a single
+// long-lived client.
+type Sink struct {
+	cfg    Config
+	client MQTT.Client
+
+	queue chan parse.LogMessage
+	lost  chan error
+	done  chan struct{}
+}
+
+// NewSink builds a Sink and starts its connect-and-publish loop in the
+// background. Construction never blocks on the network; messages queue
+// until the first connection succeeds.
+func NewSink(cfg Config) (*Sink, error) {
+	s := &Sink{
+		cfg:   cfg,
+		queue: make(chan parse.LogMessage, queueDepth),
+		lost:  make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+
+	opts := MQTT.NewClientOptions()
+	opts.AddBroker(cfg.Broker)
+	opts.SetClientID(cfg.ClientID)
+	opts.SetAutoReconnect(false) // we drive reconnects ourselves to control backoff
+	opts.SetConnectionLostHandler(func(_ MQTT.Client, err error) {
+		select {
+		case s.lost <- err:
+		default:
+		}
+	})
+
+	if strings.HasPrefix(cfg.Broker, "ssl://") || strings.HasPrefix(cfg.Broker, "tls://") {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	s.client = MQTT.NewClient(opts)
+
+	go s.run()
+
+	return s, nil
+}
+
+// newTLSConfig builds a tls.Config from the CA bundle and client
+// certificate/key named in cfg.
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	certpool := x509.NewCertPool()
+	if cfg.CACert != "" {
+		pemCerts, err := ioutil.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: read ca cert: %w", err)
+		}
+		certpool.AppendCertsFromPEM(pemCerts)
+	}
+
+	tlsConfig := &tls.Config{RootCAs: certpool}
+
+	if cfg.ClientCert != "" && cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: load client cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Publish queues msg for delivery, honoring output.Sink's never-block
+// contract. Unlike a plain drop-newest queue, a full queue here drops the
+// oldest pending message to make room, since a live feed is more useful
+// than a stale backlog.
+func (s *Sink) Publish(msg parse.LogMessage) error {
+	select {
+	case s.queue <- msg:
+	default:
+		select {
+		case <-s.queue:
+		default:
+		}
+		select {
+		case s.queue <- msg:
+		default:
+		}
+		log.Printf("mqtt: queue full, dropped oldest message to make room for %d", msg.MeterID())
+	}
+	return nil
+}
+
+// Close stops the publish loop and disconnects from the broker.
+func (s *Sink) Close() error {
+	close(s.done)
+	if s.client.IsConnected() {
+		s.client.Disconnect(250)
+	}
+	return nil
+}
+
+// run owns the client's lifecycle: connect with backoff, publish until the
+// connection is lost, then reconnect.
+func (s *Sink) run() {
+	backoff := minBackoff
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		if token := s.client.Connect(); token.Wait() && token.Error() != nil {
+			log.Printf("mqtt: connect failed, retrying in %s: %v", backoff, token.Error())
+			if !s.sleep(backoff) {
+				return
+			}
+			backoff = next(backoff)
+			continue
+		}
+
+		backoff = minBackoff
+
+		if !s.publishUntilLost() {
+			return
+		}
+	}
+}
+
+// publishUntilLost drains the queue to the broker until the connection
+// drops or the sink is closed, reporting whether the sink is still live.
+func (s *Sink) publishUntilLost() bool {
+	for {
+		select {
+		case <-s.done:
+			return false
+		case err := <-s.lost:
+			delay := minBackoff
+			if r, ok := err.(reconnecter); ok {
+				delay = r.ReconnectAfter()
+			}
+			log.Printf("mqtt: connection lost, reconnecting in %s: %v", delay, err)
+			return s.sleep(delay)
+		case msg := <-s.queue:
+			topic := strings.Replace(s.cfg.Topic, placeholder, fmt.Sprint(msg.MeterID()), 1)
+
+			payload, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("mqtt: marshal message for %d: %v", msg.MeterID(), err)
+				continue
+			}
+
+			token := s.client.Publish(topic, s.cfg.QoS, s.cfg.Retain, payload)
+			if token.Wait() && token.Error() != nil {
+				log.Printf("mqtt: publish to %q: %v", topic, token.Error())
+			}
+		}
+	}
+}
+
+// sleep waits for d or returns early if the sink is closed, reporting
+// whether the sink is still live.
+func (s *Sink) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-s.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// next advances a truncated exponential backoff with jitter:
+// min(cap, 2^n) + rand[0, 1s). cur may carry the previous step's jitter;
+// truncating it to whole seconds recovers 2^n, since minBackoff and
+// maxBackoff are whole seconds and the jitter is under one.
+func next(cur time.Duration) time.Duration {
+	d := cur.Truncate(time.Second) * 2
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(time.Second)))
+}
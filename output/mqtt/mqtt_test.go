@@ -0,0 +1,32 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	d := minBackoff
+
+	for i := 0; i < 10; i++ {
+		d = next(d)
+
+		lo := minBackoff * 2
+		hi := maxBackoff + time.Second
+		if d < lo || d >= hi {
+			t.Fatalf("next() = %s, want in [%s, %s)", d, lo, hi)
+		}
+	}
+}
+
+func TestNextBackoffCaps(t *testing.T) {
+	d := maxBackoff
+
+	for i := 0; i < 5; i++ {
+		d = next(d)
+
+		if d < maxBackoff || d >= maxBackoff+time.Second {
+			t.Fatalf("next() at cap = %s, want in [%s, %s)", d, maxBackoff, maxBackoff+time.Second)
+		}
+	}
+}
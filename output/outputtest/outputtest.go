@@ -0,0 +1,20 @@
+// Package outputtest provides a minimal parse.Message stand-in shared by
+// the output/* sink tests, so each of them doesn't need a protocol package
+// (scm, idm, ...) to depend on just to build a parse.LogMessage.
+package outputtest
+
+// Message is a minimal parse.Message. MeterID and MsgType default to their
+// zero values; set them explicitly where a test asserts on them.
+type Message struct {
+	ID          uint32
+	Type        string
+	Consumption uint32
+}
+
+func (m Message) Idx() int                 { return 0 }
+func (m Message) MsgType() string          { return m.Type }
+func (m Message) MeterID() uint32          { return m.ID }
+func (m Message) MeterType() uint8         { return 0 }
+func (m Message) Checksum() []byte         { return nil }
+func (m Message) ConsumptionValue() uint32 { return m.Consumption }
+func (m Message) Record() []string         { return nil }
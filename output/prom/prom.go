@@ -0,0 +1,99 @@
+// Package prom implements an output.Sink that exposes decoded messages as
+// Prometheus metrics on a scrape endpoint.
+package prom
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+func init() {
+	output.Register("prom", newSinkFromURL)
+}
+
+// newSinkFromURL builds a Sink from a "-sink=prom://host:port/path" flag.
+// If path is empty it defaults to "/metrics".
+func newSinkFromURL(u *url.URL) (output.Sink, error) {
+	path := u.Path
+	if path == "" {
+		path = "/metrics"
+	}
+
+	s := NewSink()
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s.Handler())
+
+	go func() {
+		if err := http.ListenAndServe(u.Host, mux); err != nil {
+			panic(fmt.Sprintf("prom: serve %s: %v", u.Host, err))
+		}
+	}()
+
+	return s, nil
+}
+
+// Sink records decoded messages as Prometheus metrics rather than
+// forwarding them anywhere; the metrics themselves are the delivery
+// mechanism, scraped by a Prometheus server.
+type Sink struct {
+	registry *prometheus.Registry
+
+	consumption *prometheus.GaugeVec
+	rssi        *prometheus.HistogramVec
+}
+
+// NewSink builds a Sink with its own Prometheus registry so it can be
+// mounted under any path without colliding with other registered metrics.
+func NewSink() *Sink {
+	s := &Sink{
+		registry: prometheus.NewRegistry(),
+		consumption: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rtlamr_consumption",
+			Help: "Last reported consumption value by meter.",
+		}, []string{"meter_id", "type"}),
+		rssi: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rtlamr_rssi",
+			Help:    "Received signal strength of decoded packets.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"meter_id", "type"}),
+	}
+
+	s.registry.MustRegister(s.consumption, s.rssi)
+
+	return s
+}
+
+// Publish records msg's consumption and RSSI as metrics. Unlike the queued
+// sinks, there's no output.Sink never-block contract to honor here: setting
+// a Prometheus gauge/histogram value is in-process and doesn't touch the
+// network, so Publish can do it synchronously.
+func (s *Sink) Publish(msg parse.LogMessage) error {
+	meterID := strconv.FormatUint(uint64(msg.MeterID()), 10)
+	msgType := msg.MsgType()
+
+	if c, ok := parse.Consumption(msg.Message); ok {
+		s.consumption.WithLabelValues(meterID, msgType).Set(float64(c))
+	}
+	s.rssi.WithLabelValues(meterID, msgType).Observe(float64(msg.RSSI))
+
+	return nil
+}
+
+func (s *Sink) Close() error {
+	return nil
+}
+
+// Handler returns an http.Handler serving this Sink's metrics in the
+// Prometheus exposition format, for embedding in another server's mux.
+func (s *Sink) Handler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,37 @@
+package prom
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bemasher/rtlamr/output/outputtest"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+// TestSinkPublishConsumption is a regression test for a build break where
+// Publish called a Consumption() method the consumption interface (now
+// parse.ConsumptionRecorder) never declared; ConsumptionValue() is the
+// accessor both that interface and scm.SCM actually expose.
+func TestSinkPublishConsumption(t *testing.T) {
+	s := NewSink()
+
+	msg := parse.LogMessage{
+		Time:    parse.Timestamp{Time: time.Unix(0, 0)},
+		Message: outputtest.Message{ID: 1, Type: "SCM", Consumption: 1234},
+	}
+
+	if err := s.Publish(msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	s.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `rtlamr_consumption{meter_id="1",type="SCM"} 1234`) {
+		t.Fatalf("expected scraped metrics to report consumption 1234, got:\n%s", body)
+	}
+}
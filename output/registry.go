@@ -0,0 +1,51 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// NewSinkFunc constructs a Sink from a parsed "-sink" URL, e.g.
+// mqtt://broker:8883/rtlamr/{meter_id} or http://collector/ingest.
+type NewSinkFunc func(u *url.URL) (Sink, error)
+
+var (
+	registryMutex sync.Mutex
+	registry      = make(map[string]NewSinkFunc)
+)
+
+// Register makes a sink implementation available under scheme, e.g. "mqtt"
+// for "-sink=mqtt://...". Sink packages call this from their init(),
+// mirroring parse.Register for message parsers.
+func Register(scheme string, newFn NewSinkFunc) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if newFn == nil {
+		panic("output: new sink func is nil")
+	}
+	if _, dup := registry[scheme]; dup {
+		panic(fmt.Sprintf("output: sink already registered (%s)", scheme))
+	}
+	registry[scheme] = newFn
+}
+
+// New parses rawURL and builds the sink registered for its scheme. Callers
+// typically invoke this once per repeated "-sink" flag.
+func New(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("output: parse sink url %q: %w", rawURL, err)
+	}
+
+	registryMutex.Lock()
+	newFn, exists := registry[u.Scheme]
+	registryMutex.Unlock()
+
+	if !exists {
+		return nil, fmt.Errorf("output: invalid sink type: %q", u.Scheme)
+	}
+
+	return newFn(u)
+}
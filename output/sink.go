@@ -0,0 +1,20 @@
+// Package output provides sinks that publish decoded messages to external
+// systems. Separating delivery from formatting means a parse.Message's
+// String() method stays pure and the decode loop never pays for a network
+// round-trip just to log a packet.
+package output
+
+import "github.com/bemasher/rtlamr/parse"
+
+// Sink receives decoded messages for delivery to an external system.
+type Sink interface {
+	// Publish delivers a single decoded message. Implementations must not
+	// block the caller on network I/O; a slow or unreachable backend should
+	// buffer internally rather than stall the decode loop.
+	Publish(parse.LogMessage) error
+
+	// Close releases the sink's underlying connection. Whether messages
+	// still buffered are delivered depends on the implementation: httpsink
+	// flushes them first, while the mqtt and influx sinks discard them.
+	Close() error
+}
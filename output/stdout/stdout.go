@@ -0,0 +1,38 @@
+// Package stdout implements the default output.Sink: the same
+// print-each-message-as-it-decodes behavior rtlamr has always had.
+package stdout
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+func init() {
+	output.Register("stdout", func(*url.URL) (output.Sink, error) {
+		return New(os.Stdout), nil
+	})
+}
+
+// Sink writes each message to an io.Writer, one per line.
+type Sink struct {
+	w io.Writer
+}
+
+// New builds a Sink that writes to w.
+func New(w io.Writer) *Sink {
+	return &Sink{w: w}
+}
+
+func (s *Sink) Publish(msg parse.LogMessage) error {
+	_, err := fmt.Fprintln(s.w, msg)
+	return err
+}
+
+func (s *Sink) Close() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+// Package boltstore implements parse.StateStore on top of BoltDB, for
+// callers that want meter state to survive a restart. A SQLite-backed
+// store would implement the same interface.
+package boltstore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/bemasher/rtlamr/parse"
+)
+
+var bucketName = []byte("meter_state")
+
+// Store is a parse.StateStore backed by a BoltDB file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) a BoltDB file at path for meter state.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: create bucket: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Get(key parse.StateKey) (state parse.MeterState, ok bool) {
+	s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get(keyBytes(key))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &state); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	return state, ok
+}
+
+func (s *Store) Set(key parse.StateKey, state parse.MeterState) {
+	s.db.Update(func(tx *bolt.Tx) error {
+		v, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketName).Put(keyBytes(key), v)
+	})
+}
+
+// Close releases the underlying BoltDB file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func keyBytes(key parse.StateKey) []byte {
+	return []byte(fmt.Sprintf("%s:%d", key.MsgType, key.MeterID))
+}
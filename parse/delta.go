@@ -0,0 +1,28 @@
+package parse
+
+// DeltaFilter matches a message only when its consumption differs from the
+// last reading stored for that meter, so a sink fed through it sees actual
+// changes instead of every retransmission of the same value. This is what
+// most home-automation setups want and previously required an external
+// script watching the raw output.
+//
+// Filter reads State.Current rather than msg itself, so it must run right
+// after State.Observe(msg, ...) records that same message — exactly how a
+// Parser's Parse (which calls Observe) feeds a FilterChain downstream.
+// Calling it against a msg that isn't the most recent Observe call for its
+// meter will compare against the wrong reading.
+type DeltaFilter struct {
+	State *State
+}
+
+func (f DeltaFilter) Filter(msg Message) bool {
+	key := StateKey{MsgType: msg.MsgType(), MeterID: msg.MeterID()}
+
+	state, exists := f.State.Current(key)
+	if !exists || !state.HasPrev {
+		// Nothing to compare against yet; let the first reading through.
+		return true
+	}
+
+	return state.Consumption != state.PrevConsumption
+}
@@ -0,0 +1,55 @@
+package parse
+
+import "container/list"
+
+// LRUStore is an in-memory StateStore that evicts the least-recently-used
+// entry once it exceeds capacity, so a long-running capture with many
+// transient meters doesn't grow without bound. It is not safe for
+// concurrent use on its own; State serializes access to it.
+type LRUStore struct {
+	capacity int
+	ll       *list.List
+	items    map[StateKey]*list.Element
+}
+
+type lruEntry struct {
+	key   StateKey
+	state MeterState
+}
+
+// NewLRUStore builds an LRUStore holding at most capacity entries.
+func NewLRUStore(capacity int) *LRUStore {
+	return &LRUStore{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[StateKey]*list.Element),
+	}
+}
+
+func (l *LRUStore) Get(key StateKey) (MeterState, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return MeterState{}, false
+	}
+	l.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).state, true
+}
+
+func (l *LRUStore) Set(key StateKey, state MeterState) {
+	if el, ok := l.items[key]; ok {
+		el.Value.(*lruEntry).state = state
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	el := l.ll.PushFront(&lruEntry{key: key, state: state})
+	l.items[key] = el
+
+	if l.ll.Len() > l.capacity {
+		oldest := l.ll.Back()
+		if oldest != nil {
+			l.ll.Remove(oldest)
+			delete(l.items, oldest.Value.(*lruEntry).key)
+		}
+	}
+}
@@ -76,6 +76,11 @@ type Parser interface {
 	Dec() *decode.Decoder
 	Cfg() *decode.PacketConfig
 	Log()
+
+	// State returns the Parser's meter-state store, so callers can tune
+	// its de-duplication window or query a meter's current reading
+	// directly instead of waiting on the next packet.
+	State() *State
 }
 
 type Message interface {
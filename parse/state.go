@@ -0,0 +1,165 @@
+package parse
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultUniqueWindow is how long after a meter was last seen any further
+// reading from it, whether its consumption changed or not, is reported as
+// not new.
+const DefaultUniqueWindow = 5 * time.Minute
+
+const defaultLRUCapacity = 4096
+
+// StateKey identifies a meter's state by message type and meter ID, since
+// the same numeric meter ID can appear under more than one protocol.
+type StateKey struct {
+	MsgType string
+	MeterID uint32
+}
+
+// MeterState is the last known reading for a meter.
+type MeterState struct {
+	Consumption uint32
+	RSSI        RSSI
+	LastSeen    time.Time
+
+	// Seq increases by one on every Observe call, regardless of message
+	// type or meter, so callers can order readings without trusting clock
+	// resolution.
+	Seq uint64
+
+	// PrevConsumption and HasPrev record the reading this one replaced, so
+	// DeltaFilter can tell a changed reading from a repeat without the
+	// comparison racing Observe's own update.
+	PrevConsumption uint32
+	HasPrev         bool
+}
+
+// StateStore persists MeterState across Parse calls and, for
+// implementations backed by disk, across process restarts. LRUStore
+// satisfies this entirely in memory; a BoltDB- or SQLite-backed store can
+// implement the same interface for durability.
+type StateStore interface {
+	Get(key StateKey) (MeterState, bool)
+	Set(key StateKey, state MeterState)
+}
+
+// State tracks the last-seen reading for every meter a Parser has decoded.
+// It carries de-duplication across Parse calls, which a Parser's per-call
+// "seen" map (still used in scm.Parser.Parse) cannot: that map only
+// de-duplicates packets within a single decode block and forgets
+// everything between calls.
+type State struct {
+	mu     sync.Mutex
+	store  StateStore
+	window time.Duration
+	seq    uint64
+}
+
+// NewState builds a State backed by store, de-duplicating repeat readings
+// seen within window of each other. A nil store defaults to an in-memory
+// LRU; a zero window disables suppression, so every decoded packet is
+// reported as new.
+func NewState(store StateStore, window time.Duration) *State {
+	if store == nil {
+		store = NewLRUStore(defaultLRUCapacity)
+	}
+	return &State{store: store, window: window}
+}
+
+// DefaultState is the State Parser implementations register meter readings
+// in by default, and that sinks query for "current" state. Sharing a
+// single instance, keyed by (MsgType, MeterID), means a sink that learns a
+// meter's RSSI only after Parse has run (e.g. server.Server.Publish, which
+// receives a full LogMessage) can patch the same record Parse created
+// instead of needing its own reference to a particular Parser.
+var DefaultState = NewState(nil, DefaultUniqueWindow)
+
+// SetWindow changes the de-duplication window, e.g. from a "-unique" flag.
+func (s *State) SetWindow(window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window = window
+}
+
+// Observe records msg's reading as of now and reports whether it should be
+// treated as new: either the meter hasn't been seen before, or window has
+// elapsed since it last was. Pass whatever RSSI is known at the call site;
+// a caller that doesn't have one yet (e.g. a Parser, which only sees
+// decoded bits) should pass the zero value and expect a later UpdateRSSI
+// call from whatever assembles the full LogMessage to fill it in.
+func (s *State) Observe(msg Message, rssi RSSI, now time.Time) (state MeterState, isNew bool) {
+	key := StateKey{MsgType: msg.MsgType(), MeterID: msg.MeterID()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, exists := s.store.Get(key)
+	isNew = !exists || now.Sub(prev.LastSeen) >= s.window
+
+	s.seq++
+	state = MeterState{
+		Consumption:     consumptionOf(msg),
+		RSSI:            rssi,
+		LastSeen:        now,
+		Seq:             s.seq,
+		PrevConsumption: prev.Consumption,
+		HasPrev:         exists,
+	}
+	s.store.Set(key, state)
+
+	return state, isNew
+}
+
+// Current returns the last known state for key, if any, without recording
+// a new observation. Sinks use this to answer "what's the current reading
+// for meter X" without waiting for the next packet.
+func (s *State) Current(key StateKey) (MeterState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Get(key)
+}
+
+// ConsumptionRecorder is implemented by messages that report a consumption
+// reading, e.g. scm.SCM. Not every Message does. It's exported so sinks
+// outside this package (output/prom, output/influx, ...) can share one
+// definition instead of each declaring their own copy.
+type ConsumptionRecorder interface {
+	ConsumptionValue() uint32
+}
+
+// Consumption returns msg's consumption reading and whether it implements
+// ConsumptionRecorder at all.
+func Consumption(msg Message) (uint32, bool) {
+	cr, ok := msg.(ConsumptionRecorder)
+	if !ok {
+		return 0, false
+	}
+	return cr.ConsumptionValue(), true
+}
+
+func consumptionOf(msg Message) uint32 {
+	c, _ := Consumption(msg)
+	return c
+}
+
+// UpdateRSSI patches the RSSI of the most recent observation for key,
+// without affecting its de-duplication window or sequence. Observe can't
+// know a packet's signal strength on its own — scm.Parser.Parse, for
+// instance, only has the decoded bits, not the RSSI computed when the
+// caller assembles a LogMessage — so whatever does have that context
+// (e.g. server.Server.Publish, which receives a full LogMessage) calls
+// this once it knows it.
+func (s *State) UpdateRSSI(key StateKey, rssi RSSI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.store.Get(key)
+	if !exists {
+		return
+	}
+	state.RSSI = rssi
+	s.store.Set(key, state)
+}
@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeMessage is a minimal Message for exercising State and DeltaFilter
+// without a protocol package (scm, idm, ...) to depend on.
+type fakeMessage struct {
+	msgType     string
+	meterID     uint32
+	consumption uint32
+}
+
+func (m fakeMessage) Idx() int                 { return 0 }
+func (m fakeMessage) MsgType() string          { return m.msgType }
+func (m fakeMessage) MeterID() uint32          { return m.meterID }
+func (m fakeMessage) MeterType() uint8         { return 0 }
+func (m fakeMessage) Checksum() []byte         { return nil }
+func (m fakeMessage) ConsumptionValue() uint32 { return m.consumption }
+func (m fakeMessage) Record() []string         { return nil }
+
+func TestLRUStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewLRUStore(2)
+
+	keyA := StateKey{MsgType: "SCM", MeterID: 1}
+	keyB := StateKey{MsgType: "SCM", MeterID: 2}
+	keyC := StateKey{MsgType: "SCM", MeterID: 3}
+
+	store.Set(keyA, MeterState{Consumption: 1})
+	store.Set(keyB, MeterState{Consumption: 2})
+
+	// Touch A so B becomes the least-recently-used entry.
+	if _, ok := store.Get(keyA); !ok {
+		t.Fatal("expected keyA to be present")
+	}
+
+	store.Set(keyC, MeterState{Consumption: 3})
+
+	if _, ok := store.Get(keyB); ok {
+		t.Fatal("expected keyB to have been evicted")
+	}
+	if _, ok := store.Get(keyA); !ok {
+		t.Fatal("expected keyA to still be present")
+	}
+	if _, ok := store.Get(keyC); !ok {
+		t.Fatal("expected keyC to be present")
+	}
+}
+
+func TestStateObserveWindow(t *testing.T) {
+	s := NewState(nil, time.Minute)
+	msg := fakeMessage{msgType: "SCM", meterID: 42, consumption: 100}
+
+	now := time.Unix(0, 0)
+
+	_, isNew := s.Observe(msg, 0, now)
+	if !isNew {
+		t.Fatal("first observation should be new")
+	}
+
+	_, isNew = s.Observe(msg, 0, now.Add(30*time.Second))
+	if isNew {
+		t.Fatal("observation within the window should not be new")
+	}
+
+	_, isNew = s.Observe(msg, 0, now.Add(2*time.Minute))
+	if !isNew {
+		t.Fatal("observation past the window should be new")
+	}
+}
+
+func TestStateUpdateRSSI(t *testing.T) {
+	s := NewState(nil, time.Minute)
+	msg := fakeMessage{msgType: "SCM", meterID: 42, consumption: 100}
+	key := StateKey{MsgType: "SCM", MeterID: 42}
+
+	s.Observe(msg, 0, time.Unix(0, 0))
+	s.UpdateRSSI(key, RSSI(-12.5))
+
+	state, ok := s.Current(key)
+	if !ok {
+		t.Fatal("expected state to exist")
+	}
+	if state.RSSI != RSSI(-12.5) {
+		t.Fatalf("RSSI = %v, want -12.5", state.RSSI)
+	}
+
+	// Updating an unknown key is a no-op, not a panic or a phantom entry.
+	s.UpdateRSSI(StateKey{MsgType: "SCM", MeterID: 99}, RSSI(-1))
+	if _, ok := s.Current(StateKey{MsgType: "SCM", MeterID: 99}); ok {
+		t.Fatal("UpdateRSSI should not create an entry for an unknown key")
+	}
+}
+
+func TestDeltaFilter(t *testing.T) {
+	// DeltaFilter.Filter is meant to run immediately after State.Observe
+	// records the same message, mirroring how a Parser's Parse (which
+	// calls Observe) feeds a FilterChain downstream.
+	s := NewState(nil, 0)
+	filter := DeltaFilter{State: s}
+
+	first := fakeMessage{msgType: "SCM", meterID: 7, consumption: 100}
+	s.Observe(first, 0, time.Unix(0, 0))
+	if !filter.Filter(first) {
+		t.Fatal("first reading should always pass")
+	}
+
+	same := fakeMessage{msgType: "SCM", meterID: 7, consumption: 100}
+	s.Observe(same, 0, time.Unix(1, 0))
+	if filter.Filter(same) {
+		t.Fatal("unchanged consumption should not pass")
+	}
+
+	changed := fakeMessage{msgType: "SCM", meterID: 7, consumption: 105}
+	s.Observe(changed, 0, time.Unix(2, 0))
+	if !filter.Filter(changed) {
+		t.Fatal("changed consumption should pass")
+	}
+}
@@ -20,64 +20,17 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strconv"
+	"time"
 
-	"crypto/tls"
-	"crypto/x509"
 	"github.com/bemasher/rtlamr/crc"
 	"github.com/bemasher/rtlamr/decode"
 	"github.com/bemasher/rtlamr/parse"
-	"io/ioutil"
-	"time"
-
-	MQTT "github.com/eclipse/paho.mqtt.golang"
 )
 
 func init() {
 	parse.Register("scm", NewParser)
 }
 
-// NewTLSConfig Setup the TLS configuration
-func NewTLSConfig() *tls.Config {
-	// Import trusted certificates from CAfile.pem.
-	// Alternatively, manually add CA certificates to
-	// default openssl CA bundle.
-	certpool := x509.NewCertPool()
-	pemCerts, err := ioutil.ReadFile("rootCA.pem")
-	if err == nil {
-		certpool.AppendCertsFromPEM(pemCerts)
-	}
-
-	// Import client certificate/key pair
-	cert, err := tls.LoadX509KeyPair("rtlsdr.certificate.crt", "rtlsdr.private.key")
-	if err != nil {
-		panic(err)
-	}
-
-	// Just to print out the client certificate..
-	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
-	if err != nil {
-		panic(err)
-	}
-	fmt.Println(cert.Leaf)
-
-	// Create tls.Config with desired tls properties
-	return &tls.Config{
-		// RootCAs = certs used to verify server cert.
-		RootCAs: certpool,
-		// ClientAuth = whether to request cert from server.
-		// Since the server is set up for SSL, this happens
-		// anyways.
-		ClientAuth: tls.NoClientCert,
-		// ClientCAs = certs used to validate client cert.
-		ClientCAs: nil,
-		// InsecureSkipVerify = verify that cert contents
-		// match server. IP matches what is in cert etc.
-		InsecureSkipVerify: true,
-		// Certificates = list of certs client sends to server.
-		Certificates: []tls.Certificate{cert},
-	}
-}
-
 func NewPacketConfig(chipLength int) (cfg decode.PacketConfig) {
 	cfg.CenterFreq = 912600155
 	cfg.DataRate = 32768
@@ -92,12 +45,14 @@ func NewPacketConfig(chipLength int) (cfg decode.PacketConfig) {
 type Parser struct {
 	decode.Decoder
 	crc.CRC
+	state *parse.State
 }
 
 func NewParser(chipLength int) (p parse.Parser) {
 	return &Parser{
 		decode.NewDecoder(NewPacketConfig(chipLength)),
 		crc.NewCRC("BCH", 0, 0x6F63, 0),
+		parse.DefaultState,
 	}
 }
 
@@ -109,7 +64,14 @@ func (p *Parser) Cfg() *decode.PacketConfig {
 	return &p.Decoder.Cfg
 }
 
+func (p *Parser) State() *parse.State {
+	return p.state
+}
+
 func (p Parser) Parse(indices []int) (msgs []parse.Message) {
+	// Packets can repeat within a single decode block; this short-circuits
+	// the checksum and state lookup for an exact repeat, independent of
+	// p.state's longer-lived de-duplication window.
 	seen := make(map[string]bool)
 
 	for _, pkt := range p.Decoder.Slice(indices) {
@@ -138,6 +100,14 @@ func (p Parser) Parse(indices []int) (msgs []parse.Message) {
 			continue
 		}
 
+		// De-duplicate across calls, not just within this one. Parse has
+		// no RSSI to report yet — only whatever assembles the full
+		// LogMessage downstream does — so this records a provisional
+		// zero RSSI; see parse.State.UpdateRSSI.
+		if _, isNew := p.state.Observe(scm, 0, time.Now()); !isNew {
+			continue
+		}
+
 		msgs = append(msgs, scm)
 	}
 
@@ -190,22 +160,13 @@ func (scm SCM) Checksum() []byte {
 	return checksum
 }
 
+// ConsumptionValue returns the meter's last reported consumption, for sinks
+// that report numeric readings (e.g. the Prometheus gauge in output/prom).
+func (scm SCM) ConsumptionValue() uint32 {
+	return scm.Consumption
+}
+
 func (scm SCM) String() string {
-	// Setup the broker connection
-	tlsconfig := NewTLSConfig()
-
-	opts := MQTT.NewClientOptions()
-	opts.AddBroker("ssl://data.iot.us-west-2.amazonaws.com:8883")
-	opts.SetClientID("rtlsdr").SetTLSConfig(tlsconfig)
-	c := MQTT.NewClient(opts)
-	if token := c.Connect(); token.Wait() && token.Error() != nil {
-		panic(token.Error())
-	}
-	// write this message out to AWS IoT
-	c.Publish("/rtlsdr", 0, false, fmt.Sprintf("{ID:%8d Type:%2d Tamper:{Phy:%02X Enc:%02X} Consumption:%8d CRC:0x%04X}",
-		scm.ID, scm.Type, scm.TamperPhy, scm.TamperEnc, scm.Consumption, scm.ChecksumVal,
-	))
-	c.Disconnect(250)
 	return fmt.Sprintf("{ID:%8d Type:%2d Tamper:{Phy:%02X Enc:%02X} Consumption:%8d CRC:0x%04X}",
 		scm.ID, scm.Type, scm.TamperPhy, scm.TamperEnc, scm.Consumption, scm.ChecksumVal,
 	)
@@ -0,0 +1,254 @@
+// Package server embeds an HTTPS endpoint that exposes decoded messages
+// live: a chunked-JSON /stream, a Prometheus /metrics, and a last-seen
+// /meters snapshot keyed by meter ID. It implements output.Sink, so it
+// slots into the same -sink flag as any other sink without touching the
+// DSP pipeline.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/bemasher/rtlamr/output"
+	"github.com/bemasher/rtlamr/output/prom"
+	"github.com/bemasher/rtlamr/parse"
+)
+
+func init() {
+	output.Register("serve", newSinkFromURL)
+}
+
+// TLSMode selects how the embedded server terminates TLS.
+type TLSMode int
+
+const (
+	// TLSOff serves plain HTTP. Only useful behind a trusted LAN or an
+	// external reverse proxy.
+	TLSOff TLSMode = iota
+	// TLSFile serves HTTPS using a cert/key pair from disk.
+	TLSFile
+	// TLSAutocert serves HTTPS using certificates obtained on demand from
+	// an ACME CA (e.g. Let's Encrypt) via golang.org/x/crypto/acme/autocert.
+	TLSAutocert
+)
+
+const defaultChallengeAddr = ":80"
+
+// Config holds the embedded server's listen address and TLS parameters.
+type Config struct {
+	Addr string
+
+	TLS TLSMode
+
+	CertFile string
+	KeyFile  string
+
+	// AutocertHosts restricts certificate issuance to these hostnames
+	// (autocert.HostWhitelist).
+	AutocertHosts []string
+	// AutocertCacheDir persists issued certificates between restarts.
+	AutocertCacheDir string
+	// ChallengeAddr serves the HTTP-01 challenge handler, default ":80".
+	ChallengeAddr string
+}
+
+// newSinkFromURL builds a Server from a "-sink=serve://..." flag, e.g.
+// serve://:8443?tls=autocert&hosts=meters.example.com&cache=/var/cache/rtlamr
+// or serve://:8443?tls=file&cert=server.crt&key=server.key.
+func newSinkFromURL(u *url.URL) (output.Sink, error) {
+	cfg := Config{
+		Addr:          u.Host,
+		ChallengeAddr: defaultChallengeAddr,
+	}
+
+	q := u.Query()
+	switch q.Get("tls") {
+	case "", "off":
+		cfg.TLS = TLSOff
+	case "file":
+		cfg.TLS = TLSFile
+		cfg.CertFile = q.Get("cert")
+		cfg.KeyFile = q.Get("key")
+	case "autocert":
+		cfg.TLS = TLSAutocert
+		if hosts := q.Get("hosts"); hosts != "" {
+			cfg.AutocertHosts = strings.Split(hosts, ",")
+		}
+		cfg.AutocertCacheDir = q.Get("cache")
+		if challenge := q.Get("challenge"); challenge != "" {
+			cfg.ChallengeAddr = challenge
+		}
+	default:
+		return nil, fmt.Errorf("server: unknown tls mode %q", q.Get("tls"))
+	}
+
+	return NewServer(cfg)
+}
+
+// Server exposes decoded messages over HTTP(S) and also implements
+// output.Sink so the decode loop can feed it directly.
+type Server struct {
+	cfg  Config
+	prom *prom.Sink
+
+	mu     sync.RWMutex
+	meters map[uint32]parse.LogMessage
+
+	subMu sync.Mutex
+	subs  map[chan parse.LogMessage]struct{}
+}
+
+// NewServer builds a Server, wires up its handlers, and starts listening in
+// the background.
+func NewServer(cfg Config) (*Server, error) {
+	s := &Server{
+		cfg:    cfg,
+		prom:   prom.NewSink(),
+		meters: make(map[uint32]parse.LogMessage),
+		subs:   make(map[chan parse.LogMessage]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", s.handleStream)
+	mux.HandleFunc("/meters", s.handleMeters)
+	mux.Handle("/metrics", s.prom.Handler())
+
+	go s.serve(mux)
+
+	return s, nil
+}
+
+func (s *Server) serve(handler http.Handler) {
+	switch s.cfg.TLS {
+	case TLSOff:
+		logListenErr(http.ListenAndServe(s.cfg.Addr, handler))
+	case TLSFile:
+		logListenErr(http.ListenAndServeTLS(s.cfg.Addr, s.cfg.CertFile, s.cfg.KeyFile, handler))
+	case TLSAutocert:
+		mgr := &autocert.Manager{
+			Prompt: autocert.AcceptTOS,
+			Cache:  autocert.DirCache(s.cfg.AutocertCacheDir),
+		}
+		if len(s.cfg.AutocertHosts) > 0 {
+			mgr.HostPolicy = autocert.HostWhitelist(s.cfg.AutocertHosts...)
+		}
+
+		go func() {
+			logListenErr(http.ListenAndServe(s.cfg.ChallengeAddr, mgr.HTTPHandler(nil)))
+		}()
+
+		httpsServer := &http.Server{
+			Addr:      s.cfg.Addr,
+			Handler:   handler,
+			TLSConfig: mgr.TLSConfig(),
+		}
+		logListenErr(httpsServer.ListenAndServeTLS("", ""))
+	}
+}
+
+// logListenErr reports a listener failure without killing the process: this
+// sink is one of several a rtlamr instance may run, and a transient bind
+// error or cert renewal hiccup here (e.g. port already in use) shouldn't
+// take down every other active sink along with it. Unlike the queued sinks,
+// there's no caller to hand the error back to — ListenAndServe* only
+// returns once the listener has already stopped serving — so this is the
+// embedded server's equivalent of their "log and keep going" contract.
+func logListenErr(err error) {
+	if err != nil {
+		log.Printf("server: %v", err)
+	}
+}
+
+// Publish records msg as the meter's last-seen state, updates its
+// Prometheus metrics, and fans it out to any open /stream connections.
+func (s *Server) Publish(msg parse.LogMessage) error {
+	s.mu.Lock()
+	s.meters[msg.MeterID()] = msg
+	s.mu.Unlock()
+
+	// Publish is the first point in the pipeline with a real RSSI, since
+	// it's computed onto LogMessage before reaching any sink; patch it
+	// into the shared state the originating Parser only recorded
+	// provisionally.
+	parse.DefaultState.UpdateRSSI(parse.StateKey{
+		MsgType: msg.MsgType(),
+		MeterID: msg.MeterID(),
+	}, msg.RSSI)
+
+	s.prom.Publish(msg)
+
+	s.subMu.Lock()
+	for sub := range s.subs {
+		select {
+		case sub <- msg:
+		default:
+		}
+	}
+	s.subMu.Unlock()
+
+	return nil
+}
+
+// Close is a no-op; the embedded HTTP(S) listener lives for the life of the
+// process.
+func (s *Server) Close() error {
+	return nil
+}
+
+// handleStream writes newly decoded messages to the client as a stream of
+// JSON objects until the client disconnects.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := make(chan parse.LogMessage, 16)
+	s.subMu.Lock()
+	s.subs[sub] = struct{}{}
+	s.subMu.Unlock()
+
+	defer func() {
+		s.subMu.Lock()
+		delete(s.subs, sub)
+		s.subMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg := <-sub:
+			if err := enc.Encode(msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleMeters returns the last-seen message for every meter that has
+// reported so far, keyed by meter ID.
+func (s *Server) handleMeters(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	snapshot := make(map[string]parse.LogMessage, len(s.meters))
+	for id, msg := range s.meters {
+		snapshot[strconv.FormatUint(uint64(id), 10)] = msg
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}